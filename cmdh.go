@@ -11,115 +11,195 @@ import (
 	"strings"
 	"time"
 
-	homedir "github.com/mitchellh/go-homedir"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-)
-
-var (
-	FlagConfigFile string
-	FlagLogFile    string
-	FlagLogLevel   string
-	FlagVerbose    bool
-	runParams      RunParameters
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 // Initialise adds global (Persistent) flags to the cobra root command
-// and sets a version string. To use cmdh, you should call cmdh.Initialise
-// from an init()  - probably in cmd/root.go. For example:
+// and binds them to a new *Options, along with the config file name,
+// search paths, accepted formats and env-var prefix that the resulting
+// config resolution will use (see WithConfigName, WithConfigPaths,
+// WithConfigTypes and WithEnvPrefix for the available overrides). Pair
+// it with Wrap to have logging and config set up automatically and the
+// resulting *Options attached to the command's context; to use cmdh,
+// you should call cmdh.Initialise (and usually Wrap) from an init() -
+// probably in cmd/root.go. For example:
 //
 //   func init() {
 //       cobra.OnInitialize(initConfig)
-//       cmdh.Initialise(rootCmd, "myapp", "v0.1.0-dev")
+//       opts := cmdh.Initialise(rootCmd, "myapp", "v0.1.0-dev")
+//       cmdh.Wrap(rootCmd, opts)
 //
-func Initialise(rootCmd *cobra.Command, tool, version string) {
-	runParams = NewRunParameters()
-	runParams.Tool = tool
-	runParams.Version = version
+func Initialise(rootCmd *cobra.Command, tool, version string, opts ...InitOption) *Options {
+	o := &Options{
+		RunParams:   NewRunParameters(),
+		configName:  tool,
+		configPaths: defaultConfigPaths(tool),
+		configTypes: []string{"yaml", "toml", "json"},
+		envPrefix:   strings.ToUpper(tool),
+	}
+	o.RunParams.Tool = tool
+	o.RunParams.Version = version
+	for _, opt := range opts {
+		opt(o)
+	}
 
 	// Persistent flags, global for the application.
-	rootCmd.PersistentFlags().StringVar(&FlagConfigFile, "config",
+	rootCmd.PersistentFlags().StringVar(&o.ConfigFile, "config",
 		"", "config file")
-	rootCmd.PersistentFlags().StringVar(&FlagLogFile, "logfile",
+	rootCmd.PersistentFlags().StringVar(&o.LogFile, "logfile",
 		"", "log file (defaults to STDERR if no file specified)")
-	rootCmd.PersistentFlags().StringVar(&FlagLogLevel, "loglevel",
-		"INFO", "log level")
-	rootCmd.PersistentFlags().BoolVar(&FlagVerbose, "verbose",
+	rootCmd.PersistentFlags().StringVar(&o.LogFormat, "log-format",
+		LogFormatText, "log format: text, json or logfmt")
+	rootCmd.PersistentFlags().StringArrayVar(&o.LogLevels, "loglevel",
+		[]string{"INFO"}, "log level, or pkg=LEVEL (repeatable) to set a level for a specific subsystem")
+	rootCmd.PersistentFlags().BoolVar(&o.Verbose, "verbose",
 		false, "turn on verbose messaging")
+	rootCmd.PersistentFlags().BoolVar(&o.LogFileAppend, "logfile-append",
+		false, "append to logfile instead of failing if it already exists")
+	rootCmd.PersistentFlags().IntVar(&o.LogFileMaxSize, "logfile-max-size",
+		0, "rotate logfile once it reaches this many megabytes (0 disables rotation)")
+	rootCmd.PersistentFlags().IntVar(&o.LogFileMaxAge, "logfile-max-age",
+		0, "maximum number of days to retain rotated logfiles (0 keeps them indefinitely)")
+	rootCmd.PersistentFlags().IntVar(&o.LogFileMaxBackups, "logfile-max-backups",
+		0, "maximum number of rotated logfiles to retain (0 keeps them all)")
+
+	_ = viper.BindPFlags(rootCmd.PersistentFlags())
+
+	// Attach o to the command's context so FromContext works even for
+	// callers who wire up logging manually instead of using Wrap. Wrap
+	// installs its own PersistentPreRunE that supersedes this one and
+	// also starts logging.
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		cmd.SetContext(WithOptions(cmd.Context(), o))
+		return nil
+	}
+
+	return o
 }
 
-// Initialise and start logging. Note that this can not happen until after
-// cobra flags have been parsed, assuming that we are allowing users to
-// set values for logfile and loglevel. You would usually call
-// StartLogging and FinishLogging from the Run func of the
-// cobra.Command. For example:
+// StartLogging initialises and starts logging using the values bound to
+// o, returning an error rather than exiting if something goes wrong.
+// Note that this can not happen until after cobra flags have been
+// parsed, assuming that we are allowing users to set values for logfile
+// and loglevel. Callers that use cmdh.Wrap do not need to call
+// StartLogging or FinishLogging themselves; they are only needed when
+// wiring logging up manually, e.g.:
 //
 //   var bamIndexCmd = &cobra.Command{
 //       Use:   "index",
 //       Short: "Tests on BAM and BAI files",
 //       Long:  `Test read from BAM/BAI files.`,
-//       Run: func(cmd *cobra.Command, args []string) {
-//           cmdh.StartLogging()
-//           bamIndexCmdRun(cmd, args)
-//           cmdh.FinishLogging()
+//       RunE: func(cmd *cobra.Command, args []string) error {
+//           o := cmdh.FromContext(cmd.Context())
+//           if err := o.StartLogging(); err != nil {
+//               return err
+//           }
+//           defer o.FinishLogging()
+//           return bamIndexCmdRun(cmd, args)
 //       },
 //   }
-func StartLogging() {
-	// Use our custom formatter
-	formatter := LogFormat{}
-	formatter.TimestampFormat = "2006-01-02 15:04:05"
-	log.SetFormatter(&formatter)
-
-	// Should fail if user-supplied logfile already exists
-	if FlagLogFile != "" {
-		file, err := os.OpenFile(FlagLogFile,
-			os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
-		if err == nil {
-			log.SetOutput(file)
-		} else {
-			// Using fmt and os.Exit - logging is not established yet.
-			fmt.Println("unable to log to file", FlagLogFile, ":", err)
-			os.Exit(1)
-		}
+func (o *Options) StartLogging() error {
+	// Select the formatter named by --log-format, defaulting to our
+	// custom human-readable formatter.
+	log.SetFormatter(formatterFor(o.LogFormat))
+
+	if err := o.openLogFile(); err != nil {
+		return err
 	}
 
-	// cobra.PersistentFlags() handles the defaulting so FlagLogLevel
-	// will be set to INFO if no level was supplied by the user.
-	switch strings.ToUpper(FlagLogLevel) {
-	case "FATAL":
-		log.SetLevel(log.FatalLevel)
-	case "WARN":
-		log.SetLevel(log.WarnLevel)
-	case "DEBUG":
-		log.SetLevel(log.DebugLevel)
-	case "INFO":
-		log.SetLevel(log.InfoLevel)
-	default:
-		// This can only happen if the user sets a loglevel and it's not
-		// one of the expected values.
-		log.Fatalf("%v is not a recognised loglevel", FlagLogLevel)
+	// Read config file (default or user-supplied) before resolving log
+	// levels, since a log.levels entry may be set there rather than on
+	// the command line.
+	if err := o.initConfig(); err != nil {
+		return err
 	}
+	log.Infof("Config file: %v", viper.ConfigFileUsed())
+
+	// Resolve --loglevel through viper so that, when the flag is not
+	// given, <PREFIX>_LOGLEVEL (see WithEnvPrefix) is honoured.
+	levelEntries := viper.GetStringSlice("loglevel")
+	if cfgLevels := viper.GetString("log.levels"); cfgLevels != "" {
+		levelEntries = append(levelEntries, cfgLevels)
+	}
+	if err := setLogLevels(levelEntries); err != nil {
+		return err
+	}
+	log.SetLevel(logLevels["*"])
 
 	// Log key execution parameters
-	log.Info("Tool: ", runParams.Tool, ` `, runParams.Version)
-	log.Info("Cmdline: ", runParams.Args)
-	log.Info("Host: ", runParams.HostName)
-	log.Infof("User: %d (%s)", runParams.UserId, runParams.UserName)
-	log.Infof("Group: %d (%s)", runParams.GroupId, runParams.GroupName)
-
-	// Read config file (default or user-supplied)
-	initConfig()
-	log.Infof("Config file: %v", viper.ConfigFileUsed())
+	log.Info("Tool: ", o.RunParams.Tool, ` `, o.RunParams.Version)
+	log.Info("Cmdline: ", o.RunParams.Args)
+	log.Info("Host: ", o.RunParams.HostName)
+	log.Infof("User: %d (%s)", o.RunParams.UserId, o.RunParams.UserName)
+	log.Infof("Group: %d (%s)", o.RunParams.GroupId, o.RunParams.GroupName)
 
-	//return true
+	return nil
 }
 
-// FinishLogging logs elapsed time.
-func FinishLogging() {
+// openLogFile directs logrus output according to o.LogFile, honouring
+// the stdout/stderr sentinel values, --logfile-append, and the
+// rotation flags.
+func (o *Options) openLogFile() error {
+	switch o.LogFile {
+	case "":
+		// Leave the logrus default (STDERR) in place.
+		o.logWriter = os.Stderr
+	case "stdout":
+		log.SetOutput(os.Stdout)
+		o.logWriter = os.Stdout
+	case "stderr":
+		log.SetOutput(os.Stderr)
+		o.logWriter = os.Stderr
+	default:
+		if o.LogFileMaxSize > 0 || o.LogFileMaxAge > 0 || o.LogFileMaxBackups > 0 {
+			// Rotation was requested, so hand the file over to lumberjack
+			// rather than opening it ourselves.
+			sink := &lumberjack.Logger{
+				Filename:   o.LogFile,
+				MaxSize:    o.LogFileMaxSize,
+				MaxAge:     o.LogFileMaxAge,
+				MaxBackups: o.LogFileMaxBackups,
+			}
+			log.SetOutput(sink)
+			o.logSink = sink
+			o.logWriter = sink
+			return nil
+		}
+
+		// Should fail if user-supplied logfile already exists, unless
+		// --logfile-append was given.
+		flags := os.O_CREATE | os.O_WRONLY | os.O_EXCL
+		if o.LogFileAppend {
+			flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		}
+		file, err := os.OpenFile(o.LogFile, flags, 0644)
+		if err != nil {
+			return fmt.Errorf("unable to log to file %s: %w", o.LogFile, err)
+		}
+		log.SetOutput(file)
+		o.logSink = file
+		o.logWriter = file
+	}
+	return nil
+}
+
+// FinishLogging logs elapsed time and flushes/closes the log sink
+// opened by openLogFile, if any (--logfile with a plain file or with
+// rotation; stdout/stderr are left open since cmdh does not own them).
+func (o *Options) FinishLogging() {
 	end := time.Now()
-	elapsed := end.Sub(runParams.StartTime)
+	elapsed := end.Sub(o.RunParams.StartTime)
 	log.Info("Elapsed time: ", elapsed)
+
+	if o.logSink != nil {
+		if err := o.logSink.Close(); err != nil {
+			fmt.Println("unable to close logfile", o.LogFile, ":", err)
+		}
+		o.logSink = nil
+	}
 }
 
 // The LogFormat struct and Format function below are based on info from:
@@ -165,37 +245,14 @@ func (f *LogFormat) Format(entry *log.Entry) ([]byte, error) {
 	return b.Bytes(), nil
 }
 
-// initConfig reads in config file and ENV variables if set. It is
-// called from StartLogging() so users do not need to call it themselves.
-func initConfig() {
-	if FlagConfigFile != "" {
-		// Use config file from the flag.
-		viper.SetConfigFile(FlagConfigFile)
-	} else {
-		// Find home directory.
-		home, err := homedir.Dir()
-		cobra.CheckErr(err)
-
-		// Search config in home directory
-		viper.AddConfigPath(home)
-	}
-
-	viper.AutomaticEnv() // read in environment variables that match
-
-	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err == nil {
-		log.Info("Using config file:", viper.ConfigFileUsed())
-	}
-}
-
 // Tool returns the name of the application. This relies on appropriate
 // values being supplied to Initialise.
-func Tool() string {
-	return runParams.Tool
+func (o *Options) Tool() string {
+	return o.RunParams.Tool
 }
 
 // Version returns the version of the application. This relies on
 // appropriate values being supplied to Initialise.
-func Version() string {
-	return runParams.Version
+func (o *Options) Version() string {
+	return o.RunParams.Version
 }
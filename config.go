@@ -0,0 +1,117 @@
+// This file implements config-file discovery for Initialise: the
+// default search paths, name and accepted formats, and the env-var
+// prefix that persistent flags, environment variables, and config files
+// all layer on top of via viper.
+
+package cmdh
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	homedir "github.com/mitchellh/go-homedir"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// InitOption configures the config-file discovery behaviour installed
+// by Initialise.
+type InitOption func(*Options)
+
+// WithConfigName overrides the default config file base name (the tool
+// name passed to Initialise) used when searching for a config file.
+func WithConfigName(name string) InitOption {
+	return func(o *Options) { o.configName = name }
+}
+
+// WithConfigPaths adds directories to search for a config file, ahead
+// of the built-in defaults (working directory, $XDG_CONFIG_HOME/<tool>,
+// /etc/<tool> and $HOME).
+func WithConfigPaths(paths ...string) InitOption {
+	return func(o *Options) { o.configPaths = append(paths, o.configPaths...) }
+}
+
+// WithConfigTypes sets the list of config file formats (e.g. "yaml",
+// "toml", "json"); the default list is yaml, toml and json. A single
+// type is passed to viper.SetConfigType, so an extension-less config
+// file, and any other file found, is parsed in that format. With more
+// than one type the list is not consulted: viper searches for every
+// extension it supports and infers the format from the file it finds.
+func WithConfigTypes(types ...string) InitOption {
+	return func(o *Options) { o.configTypes = types }
+}
+
+// WithEnvPrefix sets the environment variable prefix bound to
+// persistent flags, e.g. WithEnvPrefix("MYAPP") lets --loglevel also be
+// set via MYAPP_LOGLEVEL. Defaults to the upper-cased tool name.
+func WithEnvPrefix(prefix string) InitOption {
+	return func(o *Options) { o.envPrefix = prefix }
+}
+
+// defaultConfigPaths returns the built-in search path list described by
+// WithConfigPaths's doc comment: working directory, $XDG_CONFIG_HOME/tool
+// (or $HOME/.config/tool if XDG_CONFIG_HOME is unset), /etc/tool, and
+// $HOME.
+func defaultConfigPaths(tool string) []string {
+	paths := []string{"."}
+
+	xdg := os.Getenv("XDG_CONFIG_HOME")
+	if xdg == "" {
+		if home, err := homedir.Dir(); err == nil {
+			xdg = filepath.Join(home, ".config")
+		}
+	}
+	if xdg != "" {
+		paths = append(paths, filepath.Join(xdg, tool))
+	}
+
+	paths = append(paths, filepath.Join("/etc", tool))
+
+	if home, err := homedir.Dir(); err == nil {
+		paths = append(paths, home)
+	}
+
+	return paths
+}
+
+// initConfig reads in config file and ENV variables if set. It is
+// called from StartLogging() so users do not need to call it themselves.
+func (o *Options) initConfig() error {
+	if o.ConfigFile != "" {
+		// Use config file from the flag.
+		viper.SetConfigFile(o.ConfigFile)
+	} else {
+		viper.SetConfigName(o.configName)
+		if len(o.configTypes) == 1 {
+			// A single accepted type disambiguates an extension-less
+			// config name; with more than one, leave it unset so viper
+			// infers the type from whichever candidate file it finds,
+			// rather than forcing every match to be parsed as the first
+			// configured type.
+			viper.SetConfigType(o.configTypes[0])
+		}
+		for _, p := range o.configPaths {
+			viper.AddConfigPath(p)
+		}
+	}
+
+	viper.SetEnvPrefix(o.envPrefix)
+	viper.AutomaticEnv() // read in environment variables that match
+
+	// If a config file is found, read it in; log which one so runs are
+	// reproducible. A missing file is not an error, since flags and the
+	// environment may be all that's configured, but any other failure
+	// (e.g. a malformed config file) is worth surfacing rather than
+	// silently falling back to flags/env.
+	if err := viper.ReadInConfig(); err != nil {
+		if _, notFound := err.(viper.ConfigFileNotFoundError); notFound {
+			log.Infof("No config file found (searched for %q in %v)", o.configName, o.configPaths)
+		} else {
+			return fmt.Errorf("reading config file: %w", err)
+		}
+	} else {
+		log.Infof("Using config file: %v (env prefix %s_)", viper.ConfigFileUsed(), o.envPrefix)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package cmdh
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDefaultConfigPathsUsesXDGConfigHome(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/xdg")
+
+	paths := defaultConfigPaths("mytool")
+
+	want := filepath.Join("/xdg", "mytool")
+	if !contains(paths, want) {
+		t.Errorf("defaultConfigPaths = %v, want it to contain %q", paths, want)
+	}
+	if !contains(paths, filepath.Join("/etc", "mytool")) {
+		t.Errorf("defaultConfigPaths = %v, want it to contain /etc/mytool", paths)
+	}
+	if !contains(paths, ".") {
+		t.Errorf("defaultConfigPaths = %v, want it to contain the working directory", paths)
+	}
+}
+
+func TestDefaultConfigPathsFallsBackToHomeConfig(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "")
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skipf("no home directory available: %v", err)
+	}
+
+	paths := defaultConfigPaths("mytool")
+
+	want := filepath.Join(home, ".config", "mytool")
+	if !contains(paths, want) {
+		t.Errorf("defaultConfigPaths = %v, want it to contain %q", paths, want)
+	}
+	if !contains(paths, home) {
+		t.Errorf("defaultConfigPaths = %v, want it to contain $HOME (%q)", paths, home)
+	}
+}
+
+func contains(haystack []string, needle string) bool {
+	for _, s := range haystack {
+		if s == needle {
+			return true
+		}
+	}
+	return false
+}
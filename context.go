@@ -0,0 +1,29 @@
+// This file provides context.Context plumbing for the slog-based
+// logging backend in format.go, so downstream tools can carry a
+// structured logger through call chains without a global.
+
+package cmdh
+
+import (
+	"context"
+	"log/slog"
+)
+
+// loggerKey is an unexported type to avoid collisions with context keys
+// from other packages.
+type loggerKey struct{}
+
+// WithLogger returns a copy of ctx carrying l, retrievable later with
+// LoggerFromContext.
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// LoggerFromContext returns the slog.Logger attached to ctx by
+// WithLogger, or slog.Default() if none was attached.
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
@@ -0,0 +1,28 @@
+package cmdh
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+)
+
+func TestLoggerFromContextReturnsAttachedLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(slog.NewTextHandler(&buf, nil))
+
+	ctx := WithLogger(context.Background(), l)
+	got := LoggerFromContext(ctx)
+
+	got.Info("hello")
+	if buf.Len() == 0 {
+		t.Error("LoggerFromContext did not return the logger attached by WithLogger")
+	}
+}
+
+func TestLoggerFromContextFallsBackToDefault(t *testing.T) {
+	got := LoggerFromContext(context.Background())
+	if got != slog.Default() {
+		t.Error("LoggerFromContext should return slog.Default() when none was attached")
+	}
+}
@@ -0,0 +1,60 @@
+// This file holds the --log-format implementations: the logrus
+// formatters selectable via StartLogging, and a parallel slog-based
+// backend for callers who would rather use the standard library's
+// structured logging API.
+
+package cmdh
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Recognised values for the --log-format flag.
+const (
+	LogFormatText   = "text"
+	LogFormatJSON   = "json"
+	LogFormatLogfmt = "logfmt"
+)
+
+// formatterFor returns the logrus.Formatter that corresponds to the
+// supplied --log-format value. Unrecognised values fall back to the
+// existing human-readable LogFormat so old behaviour is preserved.
+func formatterFor(format string) log.Formatter {
+	switch strings.ToLower(format) {
+	case LogFormatJSON:
+		return &log.JSONFormatter{TimestampFormat: "2006-01-02 15:04:05"}
+	case LogFormatLogfmt:
+		return &log.TextFormatter{
+			DisableColors:   true,
+			FullTimestamp:   true,
+			TimestampFormat: "2006-01-02 15:04:05",
+		}
+	default:
+		return &LogFormat{TimestampFormat: "2006-01-02 15:04:05"}
+	}
+}
+
+// NewSlogLogger returns an slog.Logger writing to w, using a handler
+// selected by the same format values as --log-format. w can be any
+// io.Writer, including the *os.File, *lumberjack.Logger or os.Stdout/
+// os.Stderr sinks that openLogFile already knows how to build. It is
+// provided as an alternative to the logrus-based StartLogging for
+// callers who want context.Context-scoped structured logging via
+// WithLogger and LoggerFromContext, without giving up the logrus
+// behaviour elsewhere in cmdh.
+func NewSlogLogger(format string, w io.Writer) *slog.Logger {
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case LogFormatJSON:
+		handler = slog.NewJSONHandler(w, nil)
+	default:
+		// slog has no native logfmt handler; its text handler already
+		// produces logfmt-style key=value output.
+		handler = slog.NewTextHandler(w, nil)
+	}
+	return slog.New(handler)
+}
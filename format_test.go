@@ -0,0 +1,74 @@
+package cmdh
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestFormatterForKnownFormats(t *testing.T) {
+	cases := []struct {
+		format string
+		want   interface{}
+	}{
+		{LogFormatJSON, &log.JSONFormatter{}},
+		{LogFormatLogfmt, &log.TextFormatter{}},
+		{LogFormatText, &LogFormat{}},
+		{"bogus", &LogFormat{}},
+		{"", &LogFormat{}},
+	}
+	for _, c := range cases {
+		got := formatterFor(c.format)
+		switch c.want.(type) {
+		case *log.JSONFormatter:
+			if _, ok := got.(*log.JSONFormatter); !ok {
+				t.Errorf("formatterFor(%q) = %T, want *logrus.JSONFormatter", c.format, got)
+			}
+		case *log.TextFormatter:
+			if _, ok := got.(*log.TextFormatter); !ok {
+				t.Errorf("formatterFor(%q) = %T, want *logrus.TextFormatter", c.format, got)
+			}
+		case *LogFormat:
+			if _, ok := got.(*LogFormat); !ok {
+				t.Errorf("formatterFor(%q) = %T, want *LogFormat", c.format, got)
+			}
+		}
+	}
+}
+
+func TestNewSlogLoggerJSON(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewSlogLogger(LogFormatJSON, &buf)
+	l.Info("hello")
+	if !strings.HasPrefix(strings.TrimSpace(buf.String()), "{") {
+		t.Errorf("NewSlogLogger(%q) did not produce JSON output: %s", LogFormatJSON, buf.String())
+	}
+	if !strings.Contains(buf.String(), `"msg":"hello"`) {
+		t.Errorf("output missing expected message: %s", buf.String())
+	}
+}
+
+func TestNewSlogLoggerDefaultsToText(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewSlogLogger("bogus", &buf)
+	l.Info("hello")
+	if strings.HasPrefix(strings.TrimSpace(buf.String()), "{") {
+		t.Errorf("NewSlogLogger(%q) should not produce JSON output: %s", "bogus", buf.String())
+	}
+	if !strings.Contains(buf.String(), "msg=hello") {
+		t.Errorf("output missing expected logfmt message: %s", buf.String())
+	}
+}
+
+func TestNewSlogLoggerAcceptsNonFileWriter(t *testing.T) {
+	// NewSlogLogger takes io.Writer rather than *os.File specifically so
+	// it can be pointed at sinks such as a bytes.Buffer or a
+	// *lumberjack.Logger, not just files.
+	var buf bytes.Buffer
+	NewSlogLogger(LogFormatText, &buf).Info("hello")
+	if buf.Len() == 0 {
+		t.Error("expected NewSlogLogger to write to a non-*os.File io.Writer")
+	}
+}
@@ -0,0 +1,130 @@
+// This file implements per-subsystem log levels, configurable via a
+// repeated --loglevel flag or a log.levels config key of the form
+// "pkg=LEVEL,pkg2=LEVEL,*=INFO".
+
+package cmdh
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	logLevelsMu sync.Mutex
+	logLevels   = map[string]log.Level{"*": log.InfoLevel}
+)
+
+// parseLogLevels turns a list of entries into a map of subsystem name to
+// logrus.Level. Each entry is either "pkg=LEVEL" or a bare "LEVEL" (or
+// "*=LEVEL"), which sets the wildcard level used for subsystems that
+// were not listed explicitly; entries may also be comma-separated
+// within a single string, as they are when read from the log.levels
+// config key. Unrecognised levels are reported as an error so they can
+// be caught at startup rather than at first log call.
+func parseLogLevels(entries []string) (map[string]log.Level, error) {
+	levels := map[string]log.Level{"*": log.InfoLevel}
+	for _, raw := range entries {
+		for _, item := range strings.Split(raw, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			pkg, levelStr, hasPkg := strings.Cut(item, "=")
+			if !hasPkg {
+				pkg, levelStr = "*", pkg
+			}
+			level, err := log.ParseLevel(strings.ToLower(levelStr))
+			if err != nil {
+				return nil, fmt.Errorf("%v is not a recognised loglevel for %q", levelStr, pkg)
+			}
+			levels[pkg] = level
+		}
+	}
+	return levels, nil
+}
+
+// setLogLevels validates and installs the per-subsystem levels that
+// Logger subsequently consults.
+func setLogLevels(entries []string) error {
+	levels, err := parseLogLevels(entries)
+	if err != nil {
+		return err
+	}
+
+	logLevelsMu.Lock()
+	logLevels = levels
+	logLevelsMu.Unlock()
+	return nil
+}
+
+// levelFor returns the configured level for subsystem, falling back to
+// the "*" wildcard level if subsystem was not listed explicitly.
+func levelFor(subsystem string) log.Level {
+	logLevelsMu.Lock()
+	defer logLevelsMu.Unlock()
+
+	if level, ok := logLevels[subsystem]; ok {
+		return level
+	}
+	return logLevels["*"]
+}
+
+// SubsystemLogger logs under a fixed "subsystem" field, checking the
+// live per-subsystem level (and the standard logger's current output
+// and formatter) on every call rather than at construction time. This
+// matters because the natural place to obtain one is a package-scope
+// var initialised before Initialise/Wrap ever run:
+//
+//	var log = cmdh.Logger("mypkg")
+//
+// A logger that snapshotted its level/output at that point would be
+// permanently stuck on the pre-startup defaults; SubsystemLogger instead
+// resolves them fresh each time one of its methods is called.
+type SubsystemLogger struct {
+	subsystem string
+}
+
+// entry builds a *log.Entry reflecting the current standard logger's
+// output and formatter, and the subsystem's currently configured level.
+func (l *SubsystemLogger) entry() *log.Entry {
+	logger := &log.Logger{
+		Out:       log.StandardLogger().Out,
+		Formatter: log.StandardLogger().Formatter,
+		Hooks:     log.StandardLogger().Hooks,
+		Level:     levelFor(l.subsystem),
+	}
+	return logger.WithField("subsystem", l.subsystem)
+}
+
+func (l *SubsystemLogger) Debug(args ...interface{}) { l.entry().Debug(args...) }
+func (l *SubsystemLogger) Info(args ...interface{})  { l.entry().Info(args...) }
+func (l *SubsystemLogger) Warn(args ...interface{})  { l.entry().Warn(args...) }
+func (l *SubsystemLogger) Error(args ...interface{}) { l.entry().Error(args...) }
+func (l *SubsystemLogger) Fatal(args ...interface{}) { l.entry().Fatal(args...) }
+func (l *SubsystemLogger) Panic(args ...interface{}) { l.entry().Panic(args...) }
+
+func (l *SubsystemLogger) Debugf(format string, args ...interface{}) {
+	l.entry().Debugf(format, args...)
+}
+func (l *SubsystemLogger) Infof(format string, args ...interface{}) { l.entry().Infof(format, args...) }
+func (l *SubsystemLogger) Warnf(format string, args ...interface{}) { l.entry().Warnf(format, args...) }
+func (l *SubsystemLogger) Errorf(format string, args ...interface{}) {
+	l.entry().Errorf(format, args...)
+}
+func (l *SubsystemLogger) Fatalf(format string, args ...interface{}) {
+	l.entry().Fatalf(format, args...)
+}
+func (l *SubsystemLogger) Panicf(format string, args ...interface{}) {
+	l.entry().Panicf(format, args...)
+}
+
+// Logger returns a SubsystemLogger scoped to subsystem, honouring
+// whatever level is currently configured for it via
+// --loglevel/log.levels (the "*" level if subsystem was not listed
+// explicitly).
+func Logger(subsystem string) *SubsystemLogger {
+	return &SubsystemLogger{subsystem: subsystem}
+}
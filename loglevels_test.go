@@ -0,0 +1,99 @@
+package cmdh
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestParseLogLevelsDefaultsToInfo(t *testing.T) {
+	levels, err := parseLogLevels(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if levels["*"] != log.InfoLevel {
+		t.Errorf("got wildcard level %v, want %v", levels["*"], log.InfoLevel)
+	}
+}
+
+func TestParseLogLevelsBareLevelSetsWildcard(t *testing.T) {
+	levels, err := parseLogLevels([]string{"DEBUG"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if levels["*"] != log.DebugLevel {
+		t.Errorf("got wildcard level %v, want %v", levels["*"], log.DebugLevel)
+	}
+}
+
+func TestParseLogLevelsPerSubsystemAndWildcard(t *testing.T) {
+	levels, err := parseLogLevels([]string{"mypkg=WARN,otherpkg=DEBUG,*=ERROR"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]log.Level{
+		"*":        log.ErrorLevel,
+		"mypkg":    log.WarnLevel,
+		"otherpkg": log.DebugLevel,
+	}
+	for pkg, level := range want {
+		if levels[pkg] != level {
+			t.Errorf("levels[%q] = %v, want %v", pkg, levels[pkg], level)
+		}
+	}
+}
+
+func TestParseLogLevelsIgnoresEmptyEntries(t *testing.T) {
+	levels, err := parseLogLevels([]string{"", "mypkg=WARN,, ", "  "})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if levels["mypkg"] != log.WarnLevel {
+		t.Errorf("levels[mypkg] = %v, want %v", levels["mypkg"], log.WarnLevel)
+	}
+	if len(levels) != 2 {
+		t.Errorf("got %d levels, want 2 (* and mypkg): %v", len(levels), levels)
+	}
+}
+
+func TestParseLogLevelsRejectsUnknownLevel(t *testing.T) {
+	if _, err := parseLogLevels([]string{"mypkg=NOTALEVEL"}); err == nil {
+		t.Error("expected an error for an unrecognised loglevel, got nil")
+	}
+}
+
+// TestLoggerResolvesLevelLive reproduces the package-scope idiom
+// `var log = cmdh.Logger("mypkg")`, which obtains a logger before
+// setLogLevels (StartLogging) has run. It must pick up a later
+// reconfiguration rather than freezing on whatever was configured (or
+// defaulted) at the time Logger was called.
+func TestLoggerResolvesLevelLive(t *testing.T) {
+	orig := log.StandardLogger().Out
+	defer func() {
+		log.StandardLogger().SetOutput(orig)
+		logLevelsMu.Lock()
+		logLevels = map[string]log.Level{"*": log.InfoLevel}
+		logLevelsMu.Unlock()
+	}()
+
+	var buf bytes.Buffer
+	log.StandardLogger().SetOutput(&buf)
+
+	l := Logger("mypkg") // obtained before any level configuration
+
+	l.Debug("before configuration")
+	if strings.Contains(buf.String(), "before configuration") {
+		t.Fatalf("logger obtained before setLogLevels should default to INFO, got: %s", buf.String())
+	}
+
+	if err := setLogLevels([]string{"mypkg=DEBUG"}); err != nil {
+		t.Fatalf("setLogLevels: %v", err)
+	}
+
+	l.Debug("after configuration")
+	if !strings.Contains(buf.String(), "after configuration") {
+		t.Errorf("logger should honour mypkg=DEBUG set after it was obtained, got: %s", buf.String())
+	}
+}
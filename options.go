@@ -0,0 +1,73 @@
+// This file defines Options, the struct that replaced cmdh's former
+// package-level flag variables, and the context.Context plumbing used to
+// carry it (and the RunParameters it holds) to subcommands.
+
+package cmdh
+
+import (
+	"context"
+	"io"
+)
+
+// Options holds the per-command configuration that used to live in
+// package-level globals (FlagConfigFile, FlagLogFile, FlagLogLevel,
+// FlagVerbose and friends). Initialise creates an *Options, binds the
+// persistent flags to its fields, and attaches it to the root command's
+// context so subcommands can retrieve it with FromContext instead of
+// reading global variables.
+type Options struct {
+	ConfigFile string
+	LogFile    string
+	LogFormat  string
+	LogLevels  []string
+	Verbose    bool
+
+	LogFileAppend     bool
+	LogFileMaxSize    int
+	LogFileMaxAge     int
+	LogFileMaxBackups int
+
+	// RunParams holds the execution parameters (tool name, version,
+	// host, user, start time, ...) gathered when Initialise was called.
+	RunParams RunParameters
+
+	// configName, configPaths, configTypes and envPrefix control config
+	// file discovery; they are set from Initialise's tool argument and
+	// the WithConfigName/WithConfigPaths/WithConfigTypes/WithEnvPrefix
+	// options, not from flags.
+	configName  string
+	configPaths []string
+	configTypes []string
+	envPrefix   string
+
+	// logSink is the *os.File or *lumberjack.Logger opened by
+	// openLogFile for a plain --logfile value, closed by FinishLogging.
+	// It is nil when logging to stdout/stderr or the default STDERR.
+	logSink io.Closer
+
+	// logWriter is the io.Writer openLogFile directed logrus output to,
+	// whatever o.LogFile resolved to (a file, a rotator, or stdout/
+	// stderr). Wrap passes it to NewSlogLogger so the slog backend
+	// writes to the same configured sink as the logrus one.
+	logWriter io.Writer
+}
+
+// optionsKey is an unexported type to avoid collisions with context keys
+// from other packages.
+type optionsKey struct{}
+
+// WithOptions returns a copy of ctx carrying o, retrievable later with
+// FromContext.
+func WithOptions(ctx context.Context, o *Options) context.Context {
+	return context.WithValue(ctx, optionsKey{}, o)
+}
+
+// FromContext returns the *Options attached to ctx by WithOptions, or
+// nil if none was attached. The cobra.Command passed to a Run func
+// carries one once its root command has been processed by Initialise,
+// so commands should call cmdh.FromContext(cmd.Context()) rather than
+// reading the (now removed) package-level flag variables.
+func FromContext(ctx context.Context) *Options {
+	o, _ := ctx.Value(optionsKey{}).(*Options)
+	return o
+}
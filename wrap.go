@@ -0,0 +1,38 @@
+// This file provides Wrap, which installs the PersistentPreRunE /
+// PersistentPostRunE pair that replaces manual StartLogging/FinishLogging
+// calls in every command's Run func.
+
+package cmdh
+
+import "github.com/spf13/cobra"
+
+// Wrap installs a PersistentPreRunE on rootCmd that attaches o (and a
+// matching slog.Logger, see LoggerFromContext) to the command's context
+// and calls o.StartLogging, and a PersistentPostRunE that calls
+// o.FinishLogging, following the PersistentPreRun: initFuncs pattern
+// common to cobra commanders. Call it after Initialise, e.g.:
+//
+//   func init() {
+//       cobra.OnInitialize(initConfig)
+//       opts := cmdh.Initialise(rootCmd, "myapp", "v0.1.0-dev")
+//       cmdh.Wrap(rootCmd, opts)
+//   }
+//
+// Commands no longer need to call StartLogging/FinishLogging themselves,
+// and errors from logging setup propagate back through cobra's Execute
+// instead of calling os.Exit.
+func Wrap(rootCmd *cobra.Command, o *Options) {
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := o.StartLogging(); err != nil {
+			return err
+		}
+		ctx := WithOptions(cmd.Context(), o)
+		ctx = WithLogger(ctx, NewSlogLogger(o.LogFormat, o.logWriter))
+		cmd.SetContext(ctx)
+		return nil
+	}
+	rootCmd.PersistentPostRunE = func(cmd *cobra.Command, args []string) error {
+		o.FinishLogging()
+		return nil
+	}
+}